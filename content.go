@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	readability "github.com/go-shiori/go-readability"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// telegramMaxMessageLen 是 Telegram 单条消息的字符上限, 超出需要分片发送
+const telegramMaxMessageLen = 4096
+
+var (
+	ContentUserAgent    *string
+	ContentFetchTimeout *time.Duration
+	ContentProxy        *string
+)
+
+func init() {
+	ContentUserAgent = flag.String("content-user-agent", "Mozilla/5.0 (compatible; rss-everyday/1.0)", "User-Agent header used when fetching full article content")
+	ContentFetchTimeout = flag.Duration("content-fetch-timeout", 15*time.Second, "Timeout for full-content fetch requests")
+	ContentProxy = flag.String("content-proxy", "", "Optional proxy URL (http/https/socks5) for full-content fetches")
+}
+
+// contentFetchClient 专门用来抓文章正文, 和抓 feed 本身的 fetchClient 区分开;
+// 要等 flag.Parse 之后才知道超时/代理配置, 所以在 initContentFetchClient 里延迟构造
+var contentFetchClient *http.Client
+
+func initContentFetchClient() {
+	transport := http.DefaultTransport
+	if *ContentProxy != "" {
+		proxyURL, err := url.Parse(*ContentProxy)
+		if err != nil {
+			log.Fatalf("content-proxy: invalid proxy url %q: %v", *ContentProxy, err)
+		}
+		transport = &http.Transport{Proxy: http.ProxyURL(proxyURL)}
+	}
+
+	contentFetchClient = &http.Client{
+		Timeout:   *ContentFetchTimeout,
+		Transport: transport,
+	}
+}
+
+// Send 覆盖 TelegramNotifier 在 FullContent 开启时的行为:
+// 抓正文 -> readability 抽取 -> 按 4096 字符切片分条发送; 抽取失败则回退成标题+链接
+func (t *TelegramNotifier) sendFullContent(ctx context.Context, post Post) error {
+	text, err := extractFullContent(ctx, post.Item.Link)
+	if err != nil {
+		debugInfof("full-content extraction failed for %s, falling back to summary: %v", post.Item.Link, err)
+		return t.sendSummary(post)
+	}
+
+	header := fmt.Sprintf("<b>%s</b>\n%s\n\n", escapeHTML(post.Item.Title), escapeHTML(post.Item.Link))
+	for _, chunk := range splitTelegramMessage(header+escapeHTML(text), telegramMaxMessageLen) {
+		msg := tgbotapi.NewMessage(t.channel, chunk)
+		msg.ParseMode = tgbotapi.ModeHTML
+		if _, err := t.bot.Send(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *TelegramNotifier) sendSummary(post Post) error {
+	_, err := t.bot.Send(tgbotapi.NewMessage(t.channel, makeDisplayMsg(post.Item)))
+	return err
+}
+
+// extractFullContent 抓取 item.Link 并用 readability 抽出正文纯文本
+func extractFullContent(ctx context.Context, link string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", *ContentUserAgent)
+
+	resp, err := contentFetchClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "" && !strings.Contains(ct, "html") {
+		return "", fmt.Errorf("unsupported content-type %q", ct)
+	}
+
+	pageURL, err := url.Parse(link)
+	if err != nil {
+		return "", err
+	}
+
+	article, err := readability.FromReader(resp.Body, pageURL)
+	if err != nil {
+		return "", err
+	}
+
+	text := strings.TrimSpace(article.TextContent)
+	if text == "" {
+		return "", fmt.Errorf("readability: empty article content")
+	}
+	return text, nil
+}
+
+// splitTelegramMessage 把长文本按 rune 切成不超过 limit 个字符的若干块,
+// 优先在换行处切; 内容经过 escapeHTML, 所以切分点还要避开 "&amp;" 这类实体中间,
+// 否则 Telegram 在 ParseMode=HTML 下会因为非法 UTF-8/无法解析的实体而拒绝消息
+func splitTelegramMessage(s string, limit int) []string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return []string{s}
+	}
+
+	var chunks []string
+	for len(runes) > limit {
+		cut := safeCut(runes, limit)
+		chunks = append(chunks, string(runes[:cut]))
+		runes = runes[cut:]
+	}
+	if len(runes) > 0 {
+		chunks = append(chunks, string(runes))
+	}
+	return chunks
+}
+
+// safeCut 在 [0, limit) 内找一个换行符作为切分点, 没有就退而求其次用 limit 本身;
+// 无论哪种情况, 都再往前回退, 避免把切分点落在一个还没闭合的 "&...;" 实体中间
+func safeCut(runes []rune, limit int) int {
+	cut := limit
+	for i := limit - 1; i > 0; i-- {
+		if runes[i] == '\n' {
+			cut = i
+			break
+		}
+	}
+
+	// HTML 实体没有比 "&amp;" 更长的, 往前扫几个字符足够判断是否切在实体中间
+	const maxEntityLen = 10
+	for i := cut - 1; i >= 0 && i > cut-maxEntityLen; i-- {
+		if runes[i] == ';' {
+			break
+		}
+		if runes[i] == '&' {
+			cut = i
+			break
+		}
+	}
+
+	if cut <= 0 {
+		cut = limit
+	}
+	return cut
+}
+
+func escapeHTML(s string) string {
+	r := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	return r.Replace(s)
+}
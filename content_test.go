@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitTelegramMessage(t *testing.T) {
+	cases := []struct {
+		name  string
+		s     string
+		limit int
+	}{
+		{"short string fits in one chunk", "hello world", 4096},
+		{"exact limit fits in one chunk", strings.Repeat("a", 10), 10},
+		{"splits on newline when possible", strings.Repeat("a", 5) + "\n" + strings.Repeat("b", 5), 7},
+		{"falls back to hard cut with no newline", strings.Repeat("a", 20), 7},
+		{"CJK content stays on rune boundaries", strings.Repeat("中文内容", 10), 7},
+		{"does not split an escaped entity", "value=1&amp;other=2", 8},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			chunks := splitTelegramMessage(tc.s, tc.limit)
+
+			var rejoined strings.Builder
+			for _, c := range chunks {
+				rejoined.WriteString(c)
+
+				if n := len([]rune(c)); n > tc.limit {
+					t.Fatalf("chunk has %d runes, limit is %d: %q", n, tc.limit, c)
+				}
+				if strings.Contains(c, "&amp") && !strings.Contains(c, "&amp;") {
+					t.Fatalf("chunk splits an HTML entity in half: %q", c)
+				}
+			}
+
+			if rejoined.String() != tc.s {
+				t.Fatalf("rejoined chunks do not reconstruct the input:\ngot:  %q\nwant: %q", rejoined.String(), tc.s)
+			}
+		})
+	}
+}
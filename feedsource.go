@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var OPMLExportPath *string
+
+func init() {
+	OPMLExportPath = flag.String("opml-export", "", "If set, write the currently loaded feed list out as OPML to this path instead of running")
+}
+
+// rssSourceClient 用来拉取远端的 -rss-filepath (http(s) url 或 github:// 简写),
+// 没有超时的话, 一个卡住的远端会让进程在 init 阶段永远挂住
+var rssSourceClient = &http.Client{Timeout: 15 * time.Second}
+
+// loadRssSource 按 -rss-filepath 的形式取回原始配置内容:
+// 本地路径 / http(s) url / github://owner/repo/path@ref 简写
+func loadRssSource(path string) ([]byte, error) {
+	switch {
+	case strings.HasPrefix(path, "github://"):
+		return fetchGithubSource(path)
+	case strings.HasPrefix(path, "http://"), strings.HasPrefix(path, "https://"):
+		return fetchHTTPSource(path)
+	default:
+		return os.ReadFile(path)
+	}
+}
+
+func fetchHTTPSource(rawURL string) ([]byte, error) {
+	resp, err := rssSourceClient.Get(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("rss-filepath: unexpected status %d fetching %s", resp.StatusCode, rawURL)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// fetchGithubSource 通过 GitHub Contents API 拉取 github://owner/repo/path@ref 指向的文件,
+// GITHUB_TOKEN 存在时带上, 用于访问私有仓库或提高限流额度
+func fetchGithubSource(ref string) ([]byte, error) {
+	owner, repo, path, gitRef, err := parseGithubShorthand(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	apiURL := fmt.Sprintf("https://api.github.com/repos/%s/%s/contents/%s", owner, repo, path)
+	if gitRef != "" {
+		apiURL += "?ref=" + url.QueryEscape(gitRef)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github.raw+json")
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := rssSourceClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("github: unexpected status %d fetching %s", resp.StatusCode, apiURL)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func parseGithubShorthand(ref string) (owner, repo, path, gitRef string, err error) {
+	rest := strings.TrimPrefix(ref, "github://")
+	if at := strings.LastIndex(rest, "@"); at != -1 {
+		gitRef = rest[at+1:]
+		rest = rest[:at]
+	}
+
+	parts := strings.SplitN(rest, "/", 3)
+	if len(parts) != 3 {
+		return "", "", "", "", fmt.Errorf("github: expected github://owner/repo/path[@ref], got %q", ref)
+	}
+	return parts[0], parts[1], parts[2], gitRef, nil
+}
+
+// OPML 结构, 只取我们关心的字段
+type opmlDocument struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Body    opmlBody `xml:"body"`
+}
+
+type opmlBody struct {
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+type opmlOutline struct {
+	Text        string        `xml:"text,attr"`
+	Title       string        `xml:"title,attr"`
+	XmlUrl      string        `xml:"xmlUrl,attr"`
+	FullContent string        `xml:"fullContent,attr,omitempty"`
+	Outlines    []opmlOutline `xml:"outline,omitempty"`
+}
+
+// looksLikeOPML 用一个简单的嗅探区分 rss.json 和 OPML, 不依赖文件扩展名
+// (因为 -rss-filepath 现在也可能是一个没有扩展名的 url)
+func looksLikeOPML(data []byte) bool {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return false
+	}
+	if trimmed[0] == '{' || trimmed[0] == '[' {
+		return false
+	}
+	return bytes.Contains(trimmed, []byte("<opml"))
+}
+
+func parseOPML(data []byte) ([]RssInfo, error) {
+	var doc opmlDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	var infos []RssInfo
+	var walk func(outlines []opmlOutline)
+	walk = func(outlines []opmlOutline) {
+		for _, o := range outlines {
+			if o.XmlUrl != "" {
+				title := o.Title
+				if title == "" {
+					title = o.Text
+				}
+				infos = append(infos, RssInfo{
+					Title:       title,
+					Url:         o.XmlUrl,
+					FullContent: o.FullContent == "true",
+				})
+			}
+			walk(o.Outlines)
+		}
+	}
+	walk(doc.Body.Outlines)
+
+	return infos, nil
+}
+
+// writeOPML 把当前加载的 RssInfos 写成 OPML, 供 -opml-export 使用
+func writeOPML(w io.Writer, infos []RssInfo) error {
+	doc := opmlDocument{Version: "2.0"}
+	for _, info := range infos {
+		doc.Body.Outlines = append(doc.Body.Outlines, opmlOutline{
+			Text:        info.Title,
+			Title:       info.Title,
+			XmlUrl:      info.Url,
+			FullContent: strconv.FormatBool(info.FullContent),
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(doc)
+}
+
+func exportOPML(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return writeOPML(f, RssInfos.RssInfo)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseGithubShorthand(t *testing.T) {
+	cases := []struct {
+		name      string
+		ref       string
+		wantOwner string
+		wantRepo  string
+		wantPath  string
+		wantRef   string
+		wantErr   bool
+	}{
+		{
+			name:      "with ref",
+			ref:       "github://3verness/rss_everyday/rss.json@main",
+			wantOwner: "3verness",
+			wantRepo:  "rss_everyday",
+			wantPath:  "rss.json",
+			wantRef:   "main",
+		},
+		{
+			name:      "without ref",
+			ref:       "github://3verness/rss_everyday/rss.json",
+			wantOwner: "3verness",
+			wantRepo:  "rss_everyday",
+			wantPath:  "rss.json",
+			wantRef:   "",
+		},
+		{
+			name:      "nested path",
+			ref:       "github://3verness/rss_everyday/configs/rss.json@v1.0.0",
+			wantOwner: "3verness",
+			wantRepo:  "rss_everyday",
+			wantPath:  "configs/rss.json",
+			wantRef:   "v1.0.0",
+		},
+		{
+			name:    "missing path segment",
+			ref:     "github://3verness/rss_everyday",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			owner, repo, path, gitRef, err := parseGithubShorthand(tc.ref)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("parseGithubShorthand(%q): expected error, got nil", tc.ref)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseGithubShorthand(%q): unexpected error: %v", tc.ref, err)
+			}
+			if owner != tc.wantOwner || repo != tc.wantRepo || path != tc.wantPath || gitRef != tc.wantRef {
+				t.Fatalf("parseGithubShorthand(%q) = (%q, %q, %q, %q), want (%q, %q, %q, %q)",
+					tc.ref, owner, repo, path, gitRef, tc.wantOwner, tc.wantRepo, tc.wantPath, tc.wantRef)
+			}
+		})
+	}
+}
+
+func TestLooksLikeOPML(t *testing.T) {
+	cases := []struct {
+		name string
+		data string
+		want bool
+	}{
+		{"json object", `{"rss_info": []}`, false},
+		{"json array", `[]`, false},
+		{"empty", "", false},
+		{"opml document", "<?xml version=\"1.0\"?>\n<opml version=\"2.0\"><body></body></opml>", true},
+		{"opml with leading whitespace", "   \n<opml version=\"1.0\"></opml>", true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := looksLikeOPML([]byte(tc.data)); got != tc.want {
+				t.Fatalf("looksLikeOPML(%q) = %v, want %v", tc.data, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseOPML(t *testing.T) {
+	data := `<?xml version="1.0"?>
+<opml version="2.0">
+  <body>
+    <outline text="folder">
+      <outline text="Blog" title="Blog" xmlUrl="https://example.com/feed.xml" fullContent="true" />
+    </outline>
+    <outline text="News" xmlUrl="https://example.com/news.xml" />
+  </body>
+</opml>`
+
+	infos, err := parseOPML([]byte(data))
+	if err != nil {
+		t.Fatalf("parseOPML: unexpected error: %v", err)
+	}
+	if len(infos) != 2 {
+		t.Fatalf("parseOPML: got %d infos, want 2", len(infos))
+	}
+
+	if infos[0].Title != "Blog" || infos[0].Url != "https://example.com/feed.xml" || !infos[0].FullContent {
+		t.Fatalf("parseOPML: unexpected first entry: %+v", infos[0])
+	}
+	if infos[1].Title != "News" || infos[1].Url != "https://example.com/news.xml" || infos[1].FullContent {
+		t.Fatalf("parseOPML: unexpected second entry: %+v", infos[1])
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	"golang.org/x/time/rate"
+)
+
+var (
+	MaxRetries         *int
+	RetryBase          *time.Duration
+	PerHostConcurrency *int
+	FetchTimeout       *time.Duration
+	PerHostMinDelay    *time.Duration
+)
+
+func init() {
+	MaxRetries = flag.Int("max-retries", 3, "Max retries for a transient feed fetch failure (network error, 5xx, 429)")
+	RetryBase = flag.Duration("retry-base", 500*time.Millisecond, "Base delay for exponential backoff between fetch retries")
+	PerHostConcurrency = flag.Int("per-host-concurrency", 2, "Max concurrent in-flight requests per feed host")
+	FetchTimeout = flag.Duration("fetch-timeout", 20*time.Second, "Per-feed fetch timeout")
+	PerHostMinDelay = flag.Duration("per-host-min-delay", 250*time.Millisecond, "Minimum delay between two requests to the same feed host")
+}
+
+// hostGate 限制单个 host 的并发数和最小请求间隔
+type hostGate struct {
+	sem     chan struct{}
+	limiter *rate.Limiter
+}
+
+func (g *hostGate) acquire(ctx context.Context) error {
+	select {
+	case g.sem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if err := g.limiter.Wait(ctx); err != nil {
+		<-g.sem
+		return err
+	}
+	return nil
+}
+
+func (g *hostGate) release() {
+	<-g.sem
+}
+
+var (
+	hostGatesMu sync.Mutex
+	hostGates   = map[string]*hostGate{}
+)
+
+func gateFor(host string) *hostGate {
+	hostGatesMu.Lock()
+	defer hostGatesMu.Unlock()
+
+	g, ok := hostGates[host]
+	if !ok {
+		g = &hostGate{
+			sem:     make(chan struct{}, *PerHostConcurrency),
+			limiter: rate.NewLimiter(rate.Every(*PerHostMinDelay), 1),
+		}
+		hostGates[host] = g
+	}
+	return g
+}
+
+// httpStatusError 包着一个可重试的 http 状态码, Retry-After (如果有的话) 一并带上
+type httpStatusError struct {
+	status     int
+	retryAfter time.Duration
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d", e.status)
+}
+
+func isRetryable(err error) bool {
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.status == http.StatusTooManyRequests || statusErr.status >= 500
+	}
+	// 非 http 状态码错误一般是网络层抖动 (超时/连接重置/DNS), 值得重试
+	return true
+}
+
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+func backoffDelay(attempt int) time.Duration {
+	delay := *RetryBase * time.Duration(int64(1)<<uint(attempt))
+	jitter := time.Duration(rand.Int63n(int64(*RetryBase) + 1))
+	return delay + jitter
+}
+
+// doFetch 发一次条件请求并解析响应; 304 时返回 (nil, nil) 表示这轮跳过
+func doFetch(ctx context.Context, rssURL string) (*gofeed.Feed, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rssURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	etag, lastModified := seenStore.ConditionalHeaders(rssURL)
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	resp, err := fetchClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		debugInfof("feed unchanged (304): url=%s", rssURL)
+		return nil, nil
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return nil, &httpStatusError{status: resp.StatusCode, retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	seenStore.SaveConditionalHeaders(rssURL, resp.Header.Get("ETag"), resp.Header.Get("Last-Modified"))
+
+	fp := gofeed.NewParser()
+	return fp.Parse(resp.Body)
+}
+
+// fetchFeed 在 per-host 并发/限速约束下抓取一个 feed, 瞬时故障按指数退避 + 抖动重试
+func fetchFeed(parentCtx context.Context, rssURL string) (*gofeed.Feed, error) {
+	u, err := url.Parse(rssURL)
+	if err != nil {
+		return nil, err
+	}
+	gate := gateFor(u.Host)
+
+	var lastErr error
+	for attempt := 0; attempt <= *MaxRetries; attempt++ {
+		ctx, cancel := context.WithTimeout(parentCtx, *FetchTimeout)
+
+		if err := gate.acquire(ctx); err != nil {
+			cancel()
+			return nil, err
+		}
+
+		feed, err := doFetch(ctx, rssURL)
+		gate.release()
+		cancel()
+
+		if err == nil {
+			debugInfof("fetch outcome: url=%s attempt=%d result=ok", rssURL, attempt+1)
+			return feed, nil
+		}
+
+		lastErr = err
+		if !isRetryable(err) || attempt == *MaxRetries {
+			debugInfof("fetch outcome: url=%s attempt=%d result=error err=%v", rssURL, attempt+1, err)
+			break
+		}
+
+		var statusErr *httpStatusError
+		backoff := time.Duration(0)
+		if errors.As(err, &statusErr) {
+			backoff = statusErr.retryAfter
+		}
+		if backoff <= 0 {
+			backoff = backoffDelay(attempt)
+		}
+
+		debugInfof("fetch outcome: url=%s attempt=%d result=retry err=%v wait=%s", rssURL, attempt+1, err, backoff)
+
+		select {
+		case <-time.After(backoff):
+		case <-parentCtx.Done():
+			return nil, parentCtx.Err()
+		}
+	}
+
+	return nil, lastErr
+}
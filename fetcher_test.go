@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	cases := []struct {
+		name string
+		v    string
+		want time.Duration
+	}{
+		{"empty", "", 0},
+		{"seconds", "5", 5 * time.Second},
+		{"zero seconds", "0", 0},
+		{"garbage falls back to zero", "not-a-date", 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := parseRetryAfter(tc.v); got != tc.want {
+				t.Fatalf("parseRetryAfter(%q) = %v, want %v", tc.v, got, tc.want)
+			}
+		})
+	}
+
+	t.Run("http-date in the future", func(t *testing.T) {
+		future := time.Now().Add(1 * time.Hour).UTC()
+		formatted := future.Format(http.TimeFormat)
+		got := parseRetryAfter(formatted)
+		if got <= 0 || got > time.Hour {
+			t.Fatalf("parseRetryAfter(%s) = %v, want something close to 1h", formatted, got)
+		}
+	})
+}
+
+func TestBackoffDelay(t *testing.T) {
+	base := 100 * time.Millisecond
+	RetryBase = &base
+
+	for attempt := 0; attempt < 4; attempt++ {
+		delay := backoffDelay(attempt)
+		minWant := base * time.Duration(int64(1)<<uint(attempt))
+		maxWant := minWant + base
+
+		if delay < minWant || delay > maxWant {
+			t.Fatalf("backoffDelay(%d) = %v, want between %v and %v", attempt, delay, minWant, maxWant)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// FeedFilters 是单个 feed (或 filters.json 里的全局规则) 的过滤配置
+type FeedFilters struct {
+	IncludeRegex      []string `json:"include_regex,omitempty"`
+	ExcludeRegex      []string `json:"exclude_regex,omitempty"`
+	MinTitleLen       int      `json:"min_title_len,omitempty"`
+	RequireCategories []string `json:"require_categories,omitempty"`
+}
+
+// compiledFilter 是 FeedFilters 编译后的版本, 正则只在启动时编译一次
+type compiledFilter struct {
+	include           []*regexp.Regexp
+	exclude           []*regexp.Regexp
+	minTitleLen       int
+	requireCategories []string
+}
+
+func compileFilter(f *FeedFilters) (*compiledFilter, error) {
+	if f == nil {
+		return nil, nil
+	}
+
+	cf := &compiledFilter{minTitleLen: f.MinTitleLen, requireCategories: f.RequireCategories}
+	for _, pattern := range f.IncludeRegex {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("filters: invalid include_regex %q: %w", pattern, err)
+		}
+		cf.include = append(cf.include, re)
+	}
+	for _, pattern := range f.ExcludeRegex {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("filters: invalid exclude_regex %q: %w", pattern, err)
+		}
+		cf.exclude = append(cf.exclude, re)
+	}
+	return cf, nil
+}
+
+// feedFilters 缓存每个 feed url 编译好的 filter
+var feedFilters = map[string]*compiledFilter{}
+
+// globalFilter 来自可选的 filters.json, 跨 feed 生效 (比如共享的屏蔽词表)
+var globalFilter *compiledFilter
+
+// initFilters 编译每个 feed 的 filters 以及可选的全局 filters.json;
+// 一个写错的正则如果被当成"没有过滤规则"悄悄放过, 对 exclude/屏蔽词表来说等于让
+// 它本该挡掉的噪音全部流过去, 所以这里选择 fail fast 而不是降级成不过滤
+func initFilters() {
+	for _, info := range RssInfos.RssInfo {
+		cf, err := compileFilter(info.Filters)
+		if err != nil {
+			log.Fatalf("filters: %s: %v", info.Url, err)
+		}
+		feedFilters[info.Url] = cf
+	}
+
+	loadGlobalFilters()
+}
+
+func loadGlobalFilters() {
+	data, err := os.ReadFile("filters.json")
+	if err != nil {
+		// filters.json 是可选的
+		return
+	}
+
+	var cfg FeedFilters
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		log.Fatalf("filters: failed to parse filters.json: %v", err)
+	}
+
+	cf, err := compileFilter(&cfg)
+	if err != nil {
+		log.Fatalf("filters: failed to compile filters.json: %v", err)
+	}
+	globalFilter = cf
+}
+
+// passesFilters 判断一个 item 是否应该被放行 (先过 feed 自己的规则, 再过全局规则)
+func passesFilters(rss RssInfo, item *gofeed.Item) bool {
+	if !passesFilter(feedFilters[rss.Url], item) {
+		return false
+	}
+	return passesFilter(globalFilter, item)
+}
+
+func passesFilter(cf *compiledFilter, item *gofeed.Item) bool {
+	if cf == nil {
+		return true
+	}
+
+	if cf.minTitleLen > 0 && len(item.Title) < cf.minTitleLen {
+		return false
+	}
+
+	if len(cf.requireCategories) > 0 && !hasAnyCategory(item.Categories, cf.requireCategories) {
+		return false
+	}
+
+	for _, re := range cf.exclude {
+		if re.MatchString(item.Title) || re.MatchString(item.Description) {
+			return false
+		}
+	}
+
+	if len(cf.include) > 0 {
+		matched := false
+		for _, re := range cf.include {
+			if re.MatchString(item.Title) || re.MatchString(item.Description) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+func hasAnyCategory(categories, required []string) bool {
+	for _, c := range categories {
+		for _, r := range required {
+			if strings.EqualFold(c, r) {
+				return true
+			}
+		}
+	}
+	return false
+}
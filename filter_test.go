@@ -0,0 +1,92 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+)
+
+func TestPassesFilter(t *testing.T) {
+	cases := []struct {
+		name string
+		f    *FeedFilters
+		item *gofeed.Item
+		want bool
+	}{
+		{
+			name: "nil filter passes everything",
+			f:    nil,
+			item: &gofeed.Item{Title: "anything"},
+			want: true,
+		},
+		{
+			name: "min title len rejects short titles",
+			f:    &FeedFilters{MinTitleLen: 10},
+			item: &gofeed.Item{Title: "short"},
+			want: false,
+		},
+		{
+			name: "min title len allows long enough titles",
+			f:    &FeedFilters{MinTitleLen: 10},
+			item: &gofeed.Item{Title: "a sufficiently long title"},
+			want: true,
+		},
+		{
+			name: "require categories rejects missing category",
+			f:    &FeedFilters{RequireCategories: []string{"golang"}},
+			item: &gofeed.Item{Categories: []string{"python"}},
+			want: false,
+		},
+		{
+			name: "require categories matches case-insensitively",
+			f:    &FeedFilters{RequireCategories: []string{"golang"}},
+			item: &gofeed.Item{Categories: []string{"GoLang"}},
+			want: true,
+		},
+		{
+			name: "exclude regex rejects matching title",
+			f:    &FeedFilters{ExcludeRegex: []string{"spam"}},
+			item: &gofeed.Item{Title: "this is spam"},
+			want: false,
+		},
+		{
+			name: "exclude regex checks description too",
+			f:    &FeedFilters{ExcludeRegex: []string{"spam"}},
+			item: &gofeed.Item{Title: "clean", Description: "contains spam"},
+			want: false,
+		},
+		{
+			name: "include regex rejects non-matching item",
+			f:    &FeedFilters{IncludeRegex: []string{"golang"}},
+			item: &gofeed.Item{Title: "rust news"},
+			want: false,
+		},
+		{
+			name: "include regex passes matching item",
+			f:    &FeedFilters{IncludeRegex: []string{"golang"}},
+			item: &gofeed.Item{Title: "golang news"},
+			want: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cf, err := compileFilter(tc.f)
+			if err != nil {
+				t.Fatalf("compileFilter: %v", err)
+			}
+			if got := passesFilter(cf, tc.item); got != tc.want {
+				t.Fatalf("passesFilter() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCompileFilterInvalidRegex(t *testing.T) {
+	if _, err := compileFilter(&FeedFilters{IncludeRegex: []string{"("}}); err == nil {
+		t.Fatal("compileFilter: expected error for invalid include_regex, got nil")
+	}
+	if _, err := compileFilter(&FeedFilters{ExcludeRegex: []string{"("}}); err == nil {
+		t.Fatal("compileFilter: expected error for invalid exclude_regex, got nil")
+	}
+}
@@ -6,7 +6,7 @@ import (
 	"flag"
 	"fmt"
 	"log"
-	"os"
+	"net/http"
 	"strings"
 	"sync"
 	"time"
@@ -17,12 +17,15 @@ import (
 
 // 基础环境配置
 var (
-	BotToken     *string
-	ChannelID    *int64
-	StartBy      *int64
-	RSSFilePath  *string
-	DebugMode    *bool
-	GoroutineNum *int
+	BotToken      *string
+	ChannelID     *int64
+	StartBy       *int64
+	RSSFilePath   *string
+	DebugMode     *bool
+	GoroutineNum  *int
+	StateFilePath *string
+	Daemon        *bool
+	PollInterval  *time.Duration
 )
 
 func TokenValid() {
@@ -31,6 +34,9 @@ func TokenValid() {
 	}
 }
 
+// seenStore 持久化记录每个 feed 已推送过的 item 以及条件请求头, 用于去重和增量拉取
+var seenStore *Store
+
 func init() {
 	BotToken = flag.String("tg-bot", "", "Telegram bot token")
 	ChannelID = flag.Int64("tg-channel", 0, "Telegram channel id")
@@ -38,10 +44,24 @@ func init() {
 	RSSFilePath = flag.String("rss-filepath", "rss.json", "Rss json file path")
 	DebugMode = flag.Bool("debug", false, "Debug mode")
 	GoroutineNum = flag.Int("goroutine-num", 5, "Goroutine num")
+	StateFilePath = flag.String("state-file", "state.json", "Path to the persistent seen-item/conditional-request state file")
+	Daemon = flag.Bool("daemon", false, "Run continuously, polling feeds on -poll-interval instead of exiting after one pass")
+	PollInterval = flag.Duration("poll-interval", 30*time.Minute, "Poll interval when running in -daemon mode")
+}
+
+// bootstrap 解析 flag 并完成真正有副作用的启动工作 (读取 rss.json, 打开状态文件等);
+// 特意不放在 init() 里, 这样 package 本身 (以及 go test) 在被加载时不会直接触发这些 IO
+func bootstrap() {
 	flag.Parse()
 
-	TokenValid()
 	GetRssInfo()
+
+	// seen 条目只要不早于 StartBy 窗口就够用 (更早的 item 反正也不会再被 GetPostInfo 选中),
+	// 留出 2x 余量应付时钟误差/迟到的 feed 更新
+	seenStore = NewStore(*StateFilePath, time.Duration(*StartBy)*time.Hour*2)
+	initContentFetchClient()
+	initNotifiers()
+	initFilters()
 }
 
 // RSS 构成阶段
@@ -50,33 +70,42 @@ type RSSInfos struct {
 }
 
 type RssInfo struct {
-	Title       string `json:"title"`
-	Url         string `json:"url"`
-	FullContent bool   `json:"full_content"`
+	Title       string            `json:"title"`
+	Url         string            `json:"url"`
+	FullContent bool              `json:"full_content"`
+	Notifiers   []json.RawMessage `json:"notifiers,omitempty"`
+	Filters     *FeedFilters      `json:"filters,omitempty"`
 }
 
 var RssInfos = RSSInfos{nil}
 
-// 从 配置文件中获取 rss 链接
+// 从 配置文件中获取 rss 链接 (本地 rss.json/OPML, http(s) url, 或 github://owner/repo/path@ref)
 // 根据 rss 链接获取更新
 func GetRssInfo() {
-	rssFile, err := os.Open(*RSSFilePath)
+	data, err := loadRssSource(*RSSFilePath)
 	if err != nil {
 		panic(err)
 	}
 
-	err = json.NewDecoder(rssFile).Decode(&RssInfos)
-	if err != nil {
-		panic(err)
+	if looksLikeOPML(data) {
+		infos, err := parseOPML(data)
+		if err != nil {
+			panic(err)
+		}
+		RssInfos = RSSInfos{RssInfo: infos}
+		return
 	}
 
+	if err := json.Unmarshal(data, &RssInfos); err != nil {
+		panic(err)
+	}
 }
 
 var (
 	// 订阅 chan
 	infoChan = make(chan RssInfo, 20)
-	// 通知 tg chan
-	tgChan = make(chan *gofeed.Item, 20)
+	// 通知 chan, Post 定义见 notifier.go
+	tgChan = make(chan *Post, 20)
 )
 
 // 根据时间筛选昨天一整天的文章
@@ -90,11 +119,11 @@ func InfoProducer(_ context.Context) {
 	}
 }
 
-func InfoComsumer(_ context.Context, done func()) {
+func InfoComsumer(ctx context.Context, done func()) {
 	defer done()
 
 	for info := range infoChan {
-		feeds := GetPostInfo(info)
+		feeds := GetPostInfo(ctx, info)
 		// 发给 tg
 		for _, feed := range feeds {
 			tgChan <- feed
@@ -124,26 +153,49 @@ func getDatetime(times ...*time.Time) *time.Time {
 	return times[len(times)-1]
 }
 
-func GetPostInfo(rss RssInfo) []*gofeed.Item {
-	var msg = make([]*gofeed.Item, 0)
+// fetchClient 用于抓取 feed 本身 (携带条件请求头), 与推送/抓全文用的 client 分开配置;
+// 不在这里设置 Timeout, 每次请求的超时交给 fetcher.go 里按 -fetch-timeout 派生的 context 控制
+var fetchClient = &http.Client{}
+
+func itemGUID(item *gofeed.Item) string {
+	if item.GUID != "" {
+		return item.GUID
+	}
+	return item.Link
+}
+
+func GetPostInfo(ctx context.Context, rss RssInfo) []*Post {
+	var msg = make([]*Post, 0)
 
 	now := time.Now().UTC()
 	startTime := now.Add(-(time.Duration(*StartBy) * time.Hour))
 	start := time.Date(startTime.Year(), startTime.Month(), startTime.Day(), startTime.Hour(), 0, 0, 0, now.Location()).Unix()
 	end := time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), 0, 0, 0, now.Location()).Unix()
 
-	fp := gofeed.NewParser()
-	feed, err := fp.ParseURL(rss.Url)
+	feed, err := fetchFeed(ctx, rss.Url)
 	if err != nil {
 		log.Printf("parse url err: url=%s, %v", rss.Url, err)
-	} else {
-		for _, item := range feed.Items {
-			debugInfof("Title=%s, Url=%s, Published=%v, Updated=%v", item.Title, item.Link, item.Published, item.Updated)
+		return msg
+	}
+	if feed == nil {
+		// 304 Not Modified, 这一轮不用管这个 feed
+		return msg
+	}
+
+	for _, item := range feed.Items {
+		debugInfof("Title=%s, Url=%s, Published=%v, Updated=%v", item.Title, item.Link, item.Published, item.Updated)
 
-			parseDatetime := getDatetime(item.PublishedParsed, item.UpdatedParsed)
-			if parseDatetime != nil && parseDatetime.Unix() >= start && parseDatetime.Unix() < end {
-				msg = append(msg, item)
+		if seenStore.Seen(rss.Url, itemGUID(item)) {
+			continue
+		}
+
+		parseDatetime := getDatetime(item.PublishedParsed, item.UpdatedParsed)
+		if parseDatetime != nil && parseDatetime.Unix() >= start && parseDatetime.Unix() < end {
+			if !passesFilters(rss, item) {
+				debugInfof("filtered out: title=%s url=%s", item.Title, item.Link)
+				continue
 			}
+			msg = append(msg, &Post{Item: item, FeedURL: rss.Url, FullContent: rss.FullContent})
 		}
 	}
 
@@ -172,34 +224,30 @@ var (
 )
 
 // 从配置文件获取推送方式
-// 使用对应的推送渠道推送文章
+// 按 feed 配置的 Notifier 列表逐个推送, 单个渠道失败不影响其它渠道
 func PushPost(ctx context.Context, done func()) {
 	defer done()
 
-	// init bot instance
-	onceLoader.Do(func() {
-		if !*DebugMode {
-			var err error
-			bot, err = tgbotapi.NewBotAPI(*BotToken)
-			if err != nil {
-				panic(err)
-			}
-		}
-	})
-
 	cnt := 0
-	for feed := range tgChan {
-		info := fmt.Sprintln(feed.Title, feed.Link)
-		log.Printf("%s", info)
+	for post := range tgChan {
+		log.Printf("%s", fmt.Sprintln(post.Item.Title, post.Item.Link))
 
 		// do not send tg when is debug mode
 		if *DebugMode {
 			continue
 		}
 
-		displayMsg := makeDisplayMsg(feed)
-		if _, err := bot.Send(tgbotapi.NewMessage(*ChannelID, displayMsg)); err != nil {
-			log.Printf("send tg err: %v\n", err)
+		sent := false
+		for _, n := range feedNotifiers[post.FeedURL] {
+			if err := n.Send(ctx, *post); err != nil {
+				log.Printf("notifier %s send err: %v\n", n.Name(), err)
+				continue
+			}
+			sent = true
+		}
+
+		if sent {
+			seenStore.MarkSeen(post.FeedURL, itemGUID(post.Item))
 		}
 
 		cnt++
@@ -210,14 +258,16 @@ func PushPost(ctx context.Context, done func()) {
 
 	// send beat package when no new msg
 	if cnt == 0 {
-		if _, err := bot.Send(tgbotapi.NewMessage(*ChannelID, "😆only beat package, no new msg")); err != nil {
-			log.Printf("send beat err: %v\n", err)
+		if bot := sharedBot(); bot != nil {
+			if _, err := bot.Send(tgbotapi.NewMessage(*ChannelID, "😆only beat package, no new msg")); err != nil {
+				log.Printf("send beat err: %v\n", err)
+			}
 		}
 	}
 }
 
-func main() {
-
+// runOnce 跑完一整轮 "生产 -> 消费 -> 推送", 一次性模式和 daemon 模式的每一轮都走这里
+func runOnce() {
 	ctx, cancel := context.WithCancel(context.Background())
 	// PushPost
 	go PushPost(ctx, cancel)
@@ -236,5 +286,35 @@ func main() {
 	close(tgChan)
 	log.Println("waiting for done")
 	<-ctx.Done()
+
+	// 整轮的 seen/条件请求头变更只在这里统一落盘一次, 而不是每条 item 都重写状态文件
+	seenStore.Flush()
 	log.Println("done ...")
 }
+
+func main() {
+	bootstrap()
+
+	if *OPMLExportPath != "" {
+		if err := exportOPML(*OPMLExportPath); err != nil {
+			log.Fatalf("opml export failed: %v", err)
+		}
+		return
+	}
+
+	if !*Daemon {
+		runOnce()
+		return
+	}
+
+	log.Printf("running in daemon mode, poll-interval=%s", PollInterval.String())
+	for {
+		runOnce()
+
+		// 每轮跑完后重建 chan, 供下一轮使用 (上一轮结束时已经被 close 掉了)
+		infoChan = make(chan RssInfo, 20)
+		tgChan = make(chan *Post, 20)
+
+		time.Sleep(*PollInterval)
+	}
+}
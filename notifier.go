@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/mmcdole/gofeed"
+)
+
+// Post 是即将推送给某个 Notifier 的一条 item, 带上它所属的 feed,
+// 这样 Notifier 在发送失败/成功时都能定位回具体 feed (比如用于去重登记)
+type Post struct {
+	Item        *gofeed.Item
+	FeedURL     string
+	FullContent bool
+}
+
+// Notifier 是一个推送渠道, rss.json 里每个 feed 可以配置多个
+type Notifier interface {
+	Name() string
+	Send(ctx context.Context, post Post) error
+}
+
+// notifierFactory 根据单个 sink 的 json 配置构造一个 Notifier
+type notifierFactory func(raw json.RawMessage) (Notifier, error)
+
+var notifierFactories = map[string]notifierFactory{
+	"telegram": newTelegramNotifier,
+	"discord":  newDiscordNotifier,
+	"slack":    newSlackNotifier,
+	"matrix":   newMatrixNotifier,
+	"webhook":  newWebhookNotifier,
+}
+
+func buildNotifier(raw json.RawMessage) (Notifier, error) {
+	var head struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(raw, &head); err != nil {
+		return nil, fmt.Errorf("notifier: invalid sink config: %w", err)
+	}
+
+	factory, ok := notifierFactories[head.Type]
+	if !ok {
+		return nil, fmt.Errorf("notifier: unknown type %q", head.Type)
+	}
+	return factory(raw)
+}
+
+// feedNotifiers 缓存每个 feed url 解析出的 Notifier 列表
+var feedNotifiers = map[string][]Notifier{}
+
+// initNotifiers 在启动时为每个 feed 构建好它的推送渠道;
+// 没有显式配置 notifiers 的 feed 沿用旧行为, 走 -tg-bot/-tg-channel 指定的 Telegram
+func initNotifiers() {
+	for _, info := range RssInfos.RssInfo {
+		var sinks []Notifier
+		for _, raw := range info.Notifiers {
+			n, err := buildNotifier(raw)
+			if err != nil {
+				log.Printf("notifier: skip invalid sink for %s: %v", info.Url, err)
+				continue
+			}
+			sinks = append(sinks, n)
+		}
+
+		if len(sinks) == 0 {
+			sinks = append(sinks, defaultTelegramNotifier())
+		}
+		feedNotifiers[info.Url] = sinks
+	}
+}
+
+// sharedBot 懒加载唯一一个 tgbotapi.BotAPI 实例, debug 模式或未配置 token 时返回 nil
+func sharedBot() *tgbotapi.BotAPI {
+	onceLoader.Do(func() {
+		if *DebugMode || *BotToken == "" {
+			return
+		}
+
+		var err error
+		bot, err = tgbotapi.NewBotAPI(*BotToken)
+		if err != nil {
+			log.Printf("telegram: failed to init bot: %v", err)
+		}
+	})
+	return bot
+}
+
+// defaultTelegramNotifier 是没有显式配置 notifiers 的 feed 的兜底渠道,
+// 沿用原来靠 -tg-bot/-tg-channel 启动的行为, 所以这里仍然要求这两个 flag 齐备
+func defaultTelegramNotifier() Notifier {
+	TokenValid()
+	return &TelegramNotifier{bot: sharedBot(), channel: *ChannelID}
+}
+
+// TelegramNotifier 是默认的推送渠道
+type TelegramNotifier struct {
+	bot     *tgbotapi.BotAPI
+	channel int64
+}
+
+func (t *TelegramNotifier) Name() string { return "telegram" }
+
+func (t *TelegramNotifier) Send(ctx context.Context, post Post) error {
+	if t.bot == nil {
+		// debug 模式下没有真实 bot 实例, 当作发送成功处理
+		return nil
+	}
+
+	if post.FullContent {
+		return t.sendFullContent(ctx, post)
+	}
+	return t.sendSummary(post)
+}
+
+func newTelegramNotifier(raw json.RawMessage) (Notifier, error) {
+	var cfg struct {
+		Channel int64 `json:"channel"`
+	}
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil, err
+	}
+	return &TelegramNotifier{bot: sharedBot(), channel: cfg.Channel}, nil
+}
+
+// httpNotifyClient 是 discord/slack/webhook 共用的 http client
+var httpNotifyClient = &http.Client{Timeout: 10 * time.Second}
+
+// WebhookNotifier 覆盖 discord/slack/通用 webhook 三种 sink,
+// 三者都是往一个 url POST 一段 json, 区别只在默认 payload 和是否允许自定义模板
+type WebhookNotifier struct {
+	name     string
+	url      string
+	template string
+	client   *http.Client
+}
+
+func (w *WebhookNotifier) Name() string { return w.name }
+
+func (w *WebhookNotifier) Send(ctx context.Context, post Post) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, strings.NewReader(w.render(post)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s webhook returned status %d", w.name, resp.StatusCode)
+	}
+	return nil
+}
+
+func (w *WebhookNotifier) render(post Post) string {
+	if w.template != "" {
+		r := strings.NewReplacer(
+			"{{title}}", post.Item.Title,
+			"{{link}}", post.Item.Link,
+		)
+		return r.Replace(w.template)
+	}
+
+	// Discord 和通用 webhook 用 "content", Slack incoming webhook 要求的是 "text"
+	field := "content"
+	if w.name == "slack" {
+		field = "text"
+	}
+
+	body, _ := json.Marshal(map[string]string{field: makeDisplayMsg(post.Item)})
+	return string(body)
+}
+
+func newDiscordNotifier(raw json.RawMessage) (Notifier, error) {
+	var cfg struct {
+		Webhook string `json:"webhook"`
+	}
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil, err
+	}
+	return &WebhookNotifier{name: "discord", url: cfg.Webhook, client: httpNotifyClient}, nil
+}
+
+func newSlackNotifier(raw json.RawMessage) (Notifier, error) {
+	var cfg struct {
+		Webhook string `json:"webhook"`
+	}
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil, err
+	}
+	return &WebhookNotifier{name: "slack", url: cfg.Webhook, client: httpNotifyClient}, nil
+}
+
+func newWebhookNotifier(raw json.RawMessage) (Notifier, error) {
+	var cfg struct {
+		Url      string `json:"url"`
+		Template string `json:"template"`
+	}
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil, err
+	}
+	return &WebhookNotifier{name: "webhook", url: cfg.Url, template: cfg.Template, client: httpNotifyClient}, nil
+}
+
+// MatrixNotifier 发到一个 matrix room, access token 不走 rss.json (避免把密钥提交进配置),
+// 而是跟 GITHUB_TOKEN 一样走环境变量
+type MatrixNotifier struct {
+	homeserver  string
+	room        string
+	accessToken string
+	client      *http.Client
+	txnSeq      uint64
+}
+
+func (m *MatrixNotifier) Name() string { return "matrix" }
+
+// nextTxnID 按 client-server spec, PUT .../send/{eventType}/{txnId} 要求每次请求带一个
+// 调用方生成的事务 id, 同一个 txnId 重复提交会被服务端去重; 这里用时间戳 + 自增序号拼出一个唯一值
+func (m *MatrixNotifier) nextTxnID() string {
+	seq := atomic.AddUint64(&m.txnSeq, 1)
+	return fmt.Sprintf("rss-everyday-%d-%d", time.Now().UnixNano(), seq)
+}
+
+func (m *MatrixNotifier) Send(ctx context.Context, post Post) error {
+	body, err := json.Marshal(map[string]string{
+		"msgtype": "m.text",
+		"body":    makeDisplayMsg(post.Item),
+	})
+	if err != nil {
+		return err
+	}
+
+	sendURL := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s?access_token=%s",
+		strings.TrimRight(m.homeserver, "/"), url.PathEscape(m.room), url.PathEscape(m.nextTxnID()), url.QueryEscape(m.accessToken))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, sendURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("matrix webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func newMatrixNotifier(raw json.RawMessage) (Notifier, error) {
+	var cfg struct {
+		Homeserver string `json:"homeserver"`
+		Room       string `json:"room"`
+	}
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil, err
+	}
+	return &MatrixNotifier{
+		homeserver:  cfg.Homeserver,
+		room:        cfg.Room,
+		accessToken: os.Getenv("MATRIX_ACCESS_TOKEN"),
+		client:      httpNotifyClient,
+	}, nil
+}
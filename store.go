@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// FeedState 记录单个 feed 的增量拉取状态:
+// 条件请求头 (用于下一次 If-None-Match/If-Modified-Since) 以及已推送过的 item guid,
+// Seen 的 value 是登记时的 unix 时间戳, 用来在 save 时按 seenTTL 裁剪掉陈旧条目
+type FeedState struct {
+	ETag         string           `json:"etag,omitempty"`
+	LastModified string           `json:"last_modified,omitempty"`
+	Seen         map[string]int64 `json:"seen"`
+}
+
+// Store 是落地到磁盘的去重/增量拉取状态, 以 feed url 为 key
+// 实现上用一个 json 文件打底, 规模足够小, 不需要引入 bolt 之类的嵌入式 db
+type Store struct {
+	mu      sync.Mutex
+	path    string
+	seenTTL time.Duration
+	dirty   bool
+	data    map[string]*FeedState
+}
+
+// NewStore 加载 path 处的状态文件; seenTTL 之外的 seen 条目会在落盘时被裁剪掉 --
+// 一个 item 的发布时间一旦落出 StartBy 窗口, GetPostInfo 就再也不会选中它,
+// 所以只要 seenTTL 不小于 StartBy 就不会引入重复推送
+func NewStore(path string, seenTTL time.Duration) *Store {
+	s := &Store{path: path, seenTTL: seenTTL, data: make(map[string]*FeedState)}
+	s.load()
+	return s
+}
+
+func (s *Store) load() {
+	f, err := os.Open(s.path)
+	if err != nil {
+		// 状态文件不存在时视为首次运行, 从空状态开始
+		return
+	}
+	defer f.Close()
+
+	if err := json.NewDecoder(f).Decode(&s.data); err != nil {
+		log.Printf("store: failed to decode state file %s, starting fresh: %v", s.path, err)
+		s.data = make(map[string]*FeedState)
+	}
+}
+
+// pruneExpired 删掉超过 seenTTL 的 seen 条目, 避免状态文件随时间无限增长
+func (s *Store) pruneExpired() {
+	if s.seenTTL <= 0 {
+		return
+	}
+
+	cutoff := time.Now().Add(-s.seenTTL).Unix()
+	for _, st := range s.data {
+		for guid, seenAt := range st.Seen {
+			if seenAt < cutoff {
+				delete(st.Seen, guid)
+			}
+		}
+	}
+}
+
+// Flush 把累积的变更落盘, 一轮 (daemon 模式) 或一次运行只需要调用一次,
+// 而不是每次 MarkSeen/SaveConditionalHeaders 都重写一遍整个文件
+func (s *Store) Flush() {
+	s.mu.Lock()
+	if !s.dirty {
+		s.mu.Unlock()
+		return
+	}
+	s.dirty = false
+	s.pruneExpired()
+	s.mu.Unlock()
+
+	s.save()
+}
+
+func (s *Store) save() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tmpPath := s.path + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		log.Printf("store: failed to write state file: %v", err)
+		return
+	}
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(s.data); err != nil {
+		log.Printf("store: failed to encode state: %v", err)
+		f.Close()
+		return
+	}
+	f.Close()
+
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		log.Printf("store: failed to persist state file: %v", err)
+	}
+}
+
+func (s *Store) stateFor(feedURL string) *FeedState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.data[feedURL]
+	if !ok {
+		st = &FeedState{Seen: make(map[string]int64)}
+		s.data[feedURL] = st
+	}
+	return st
+}
+
+// ConditionalHeaders 返回上一次成功拉取 feedURL 时记下的 ETag/Last-Modified
+func (s *Store) ConditionalHeaders(feedURL string) (etag, lastModified string) {
+	st := s.stateFor(feedURL)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return st.ETag, st.LastModified
+}
+
+// SaveConditionalHeaders 在一次成功的 200 响应后更新条件请求头, 实际落盘要等下一次 Flush
+func (s *Store) SaveConditionalHeaders(feedURL, etag, lastModified string) {
+	st := s.stateFor(feedURL)
+
+	s.mu.Lock()
+	st.ETag = etag
+	st.LastModified = lastModified
+	s.dirty = true
+	s.mu.Unlock()
+}
+
+// Seen 判断某条 item 是否已经推送过
+func (s *Store) Seen(feedURL, guid string) bool {
+	st := s.stateFor(feedURL)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := st.Seen[guid]
+	return ok
+}
+
+// MarkSeen 在推送成功后登记 item, 避免下一轮重复推送; 实际落盘要等下一次 Flush
+func (s *Store) MarkSeen(feedURL, guid string) {
+	st := s.stateFor(feedURL)
+
+	s.mu.Lock()
+	st.Seen[guid] = time.Now().Unix()
+	s.dirty = true
+	s.mu.Unlock()
+}